@@ -1,12 +1,16 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/kyokomi/emoji"
 	"github.com/pkg/errors"
@@ -18,6 +22,28 @@ type Component struct {
 	Source string
 	Method string
 
+	// Branch, Tag, and Commit pin a git subcomponent to a specific ref.
+	// At most one should be set; Commit takes precedence over Tag, which
+	// takes precedence over Branch. When none are set, the default branch
+	// of Source is cloned.
+	Branch string
+	Tag    string
+	Commit string
+
+	// Auth holds credentials for fetching a private git subcomponent. It is
+	// resolved against FABRIKATE_GIT_* environment variables when left unset.
+	Auth GitAuth
+
+	// SHA256 optionally pins the expected checksum of an "http" sourced
+	// archive; Install refuses to extract a download that doesn't match.
+	SHA256 string
+
+	// Environments declares the inheritance chain for named environments,
+	// e.g. {"prod": {"inherits": ["staging"]}}. LoadConfig consults this
+	// before falling back to the "inherits" key convention inside the
+	// environment's own config file.
+	Environments map[string]EnvironmentSpec `json:"environments,omitempty"`
+
 	Generator     string
 	Subcomponents []Component
 	Repo          string
@@ -72,23 +98,45 @@ func (c *Component) MergeConfigFile(path string) (err error) {
 	return nil
 }
 
+// LoadConfig merges the config chain for environment into c.Config. The
+// chain is resolved from the "environments" declarations in component.json
+// and/or the "inherits" convention inside each environment's own config
+// file (see resolveEnvironmentChain), ordered from most-general to
+// most-specific. Since ComponentConfig.Merge only fills in keys that aren't
+// already set, the chain is applied most-specific first so a descendant's
+// values win over the ancestors it inherits from; common.json is always
+// consulted last unless it already appears explicitly in the chain.
 func (c *Component) LoadConfig(environment string) (err error) {
-	environmentFileName := fmt.Sprintf("%s.json", environment)
-	environmentConfigPath := path.Join(c.PhysicalPath, "config", environmentFileName)
-	if err := c.MergeConfigFile(environmentConfigPath); err != nil {
+	chain, err := c.resolveEnvironmentChain(environment)
+	if err != nil {
 		return err
 	}
 
-	commonPath := path.Join(c.PhysicalPath, "config", "common.json")
-	if err := c.MergeConfigFile(commonPath); err != nil {
-		return err
+	mergedCommon := false
+	for i := len(chain) - 1; i >= 0; i-- {
+		env := chain[i]
+		if env == "common" {
+			mergedCommon = true
+		}
+
+		envConfigPath := path.Join(c.PhysicalPath, "config", fmt.Sprintf("%s.json", env))
+		if err := c.MergeConfigFile(envConfigPath); err != nil {
+			return err
+		}
+	}
+
+	if !mergedCommon {
+		commonPath := path.Join(c.PhysicalPath, "config", "common.json")
+		if err := c.MergeConfigFile(commonPath); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (c *Component) RelativePathTo() string {
-	if c.Method == "git" {
+	if c.Method == "git" || c.Method == "http" || c.Method == "https" {
 		return fmt.Sprintf("components/%s", c.Name)
 	} else if c.Source != "" {
 		return c.Name
@@ -99,20 +147,36 @@ func (c *Component) RelativePathTo() string {
 
 func (c *Component) Install(componentPath string) (err error) {
 	for _, subcomponent := range c.Subcomponents {
-		if subcomponent.Method == "git" {
+		switch subcomponent.Method {
+		case "git":
 			componentsPath := fmt.Sprintf("%s/components", componentPath)
-			if err := exec.Command("mkdir", "-p", componentsPath).Run(); err != nil {
-				return err
+			if err := os.MkdirAll(componentsPath, 0755); err != nil {
+				return errors.Wrapf(err, "creating components directory %s", componentsPath)
 			}
 
 			subcomponentPath := path.Join(componentPath, subcomponent.RelativePathTo())
-			if err = exec.Command("rm", "-rf", subcomponentPath).Run(); err != nil {
-				return err
+			if err = os.RemoveAll(subcomponentPath); err != nil {
+				return errors.Wrapf(err, "removing existing component at %s", subcomponentPath)
+			}
+
+			log.Println(emoji.Sprintf(":helicopter: installing component %s with git from %s@%s", subcomponent.Name, subcomponent.Source, ref(subcomponent)))
+			if err = defaultComponentCache.Fetch(subcomponent, subcomponentPath, defaultGitFetcher); err != nil {
+				return errors.Wrapf(err, "installing component %s", subcomponent.Name)
 			}
 
-			log.Println(emoji.Sprintf(":helicopter: installing component %s with git from %s", subcomponent.Name, subcomponent.Source))
-			if err = exec.Command("git", "clone", subcomponent.Source, subcomponentPath).Run(); err != nil {
-				return err
+		case "http", "https":
+			componentsPath := fmt.Sprintf("%s/components", componentPath)
+			if err := os.MkdirAll(componentsPath, 0755); err != nil {
+				return errors.Wrapf(err, "creating components directory %s", componentsPath)
+			}
+
+			subcomponentPath := path.Join(componentPath, subcomponent.RelativePathTo())
+			if err = os.RemoveAll(subcomponentPath); err != nil {
+				return errors.Wrapf(err, "removing existing component at %s", subcomponentPath)
+			}
+
+			if err = fetchHTTPComponent(subcomponent, subcomponentPath); err != nil {
+				return errors.Wrapf(err, "installing component %s", subcomponent.Name)
 			}
 		}
 	}
@@ -120,8 +184,18 @@ func (c *Component) Install(componentPath string) (err error) {
 	return nil
 }
 
+// ComponentIteration must be goroutine-safe: IterateComponentTree may invoke
+// it concurrently from multiple worker goroutines, one per in-flight
+// component.
 type ComponentIteration func(path string, component *Component) (err error)
 
+// IterateOptions configures the traversal performed by IterateComponentTree.
+type IterateOptions struct {
+	// Concurrency is the number of worker goroutines processing components
+	// at once. Defaults to runtime.NumCPU() when <= 0.
+	Concurrency int
+}
+
 // IterateComponentTree is a general function used for iterating a deployment tree for installing, generating, etc.
 
 // It takes a starting path that is expected to have a component.json in it. It is assumed to be an error in this step of
@@ -131,10 +205,18 @@ type ComponentIteration func(path string, component *Component) (err error)
 // and then for each subcomponent specified it determines if it is a simple subdirectory of if it (<subcomponent path>) is
 // an installed component in components and requires a two level path addition (components/<subcomponent name>).
 
-func IterateComponentTree(startingPath string, environment string, componentIteration ComponentIteration) (completedComponents []Component, err error) {
-	queue := make([]Component, 0)
+// Components are processed concurrently by a bounded pool of workers rather
+// than one at a time, since each component's iteration may do its own I/O
+// (a git clone, a helm template render, etc). The order components are
+// discovered in is therefore not preserved; completedComponents is sorted by
+// LogicalPath before returning so callers still see a deterministic result.
+func IterateComponentTree(startingPath string, environment string, componentIteration ComponentIteration, opts IterateOptions) (completedComponents []Component, err error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
 
-	component := Component{
+	root := Component{
 		PhysicalPath: startingPath,
 		LogicalPath:  "./",
 		Config: ComponentConfig{
@@ -143,45 +225,117 @@ func IterateComponentTree(startingPath string, environment string, componentIter
 		},
 	}
 
-	queue = append(queue, component)
-	completedComponents = make([]Component, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	for len(queue) != 0 {
-		component := queue[0]
-		queue = queue[1:]
+	workQueue := make(chan Component, 1024)
+	workQueue <- root
 
-		component, err := component.LoadComponent()
-		if err != nil {
-			return nil, err
-		}
+	var inFlight int64 = 1
+	var closeOnce sync.Once
 
-		if err := component.LoadConfig(environment); err != nil {
-			return nil, err
+	var resultsMu sync.Mutex
+	completedComponents = make([]Component, 0)
+	var firstErr error
+
+	recordErr := func(e error) {
+		resultsMu.Lock()
+		defer resultsMu.Unlock()
+		if firstErr == nil {
+			firstErr = e
+			cancel()
 		}
+	}
 
-		if err = componentIteration(component.PhysicalPath, &component); err != nil {
-			return nil, err
+	finishOne := func() {
+		if atomic.AddInt64(&inFlight, -1) == 0 {
+			closeOnce.Do(func() { close(workQueue) })
 		}
+	}
 
-		completedComponents = append(completedComponents, component)
-
-		for _, subcomponent := range component.Subcomponents {
-			// if subcomponent is inlined, it doesn't need further processing and we are done.
-			if subcomponent.Source == "" {
-				continue
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case component, ok := <-workQueue:
+					if !ok {
+						return
+					}
+
+					loaded, loadErr := component.LoadComponent()
+					if loadErr != nil {
+						recordErr(loadErr)
+						finishOne()
+						continue
+					}
+
+					if configErr := loaded.LoadConfig(environment); configErr != nil {
+						recordErr(configErr)
+						finishOne()
+						continue
+					}
+
+					if iterErr := componentIteration(loaded.PhysicalPath, &loaded); iterErr != nil {
+						recordErr(iterErr)
+						finishOne()
+						continue
+					}
+
+					resultsMu.Lock()
+					completedComponents = append(completedComponents, loaded)
+					resultsMu.Unlock()
+
+					children := make([]Component, 0, len(loaded.Subcomponents))
+					for _, subcomponent := range loaded.Subcomponents {
+						// if subcomponent is inlined, it doesn't need further processing and we are done.
+						if subcomponent.Source == "" {
+							continue
+						}
+
+						children = append(children, Component{
+							Name:         subcomponent.Name,
+							PhysicalPath: path.Join(loaded.PhysicalPath, subcomponent.RelativePathTo()),
+							LogicalPath:  path.Join(loaded.LogicalPath, subcomponent.Name),
+							Config:       loaded.Config.Subcomponents[subcomponent.Name],
+						})
+					}
+
+					if len(children) > 0 {
+						atomic.AddInt64(&inFlight, int64(len(children)))
+						// Enqueue asynchronously so this worker can't deadlock against
+						// other workers that are themselves blocked sending on a full
+						// workQueue.
+						go func(children []Component) {
+							for _, child := range children {
+								log.Debugf("adding subcomponent '%s' to queue with physical path '%s' and logical path '%s'\n", child.Name, child.PhysicalPath, child.LogicalPath)
+								select {
+								case workQueue <- child:
+								case <-ctx.Done():
+								}
+							}
+						}(children)
+					}
+
+					finishOne()
+				}
 			}
+		}()
+	}
 
-			componentToQueue := Component{
-				Name:         subcomponent.Name,
-				PhysicalPath: path.Join(component.PhysicalPath, subcomponent.RelativePathTo()),
-				LogicalPath:  path.Join(component.LogicalPath, subcomponent.Name),
-				Config:       component.Config.Subcomponents[subcomponent.Name],
-			}
+	wg.Wait()
 
-			log.Debugf("adding subcomponent '%s' to queue with physical path '%s' and logical path '%s'\n", componentToQueue.Name, componentToQueue.PhysicalPath, componentToQueue.LogicalPath)
-			queue = append(queue, componentToQueue)
-		}
+	if firstErr != nil {
+		return nil, firstErr
 	}
 
+	sort.Slice(completedComponents, func(i, j int) bool {
+		return completedComponents[i].LogicalPath < completedComponents[j].LogicalPath
+	})
+
 	return completedComponents, nil
 }
\ No newline at end of file
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ComponentCache is a content-addressed, on-disk cache of installed git
+// subcomponents keyed by resolved commit SHA. It lets repeated `fabrikate
+// generate` runs over the same stack (the common case in CI) skip the
+// clone entirely once a ref has been fetched once.
+type ComponentCache struct {
+	// Dir is the cache root; each entry lives at Dir/<sha>.
+	Dir string
+
+	// Offline, when true, refuses any network access: resolving an unpinned
+	// ref and fetching a cache miss both become errors instead of falling
+	// through to the network. Intended to back a `fabrikate generate
+	// --offline` CLI flag, but this repo has no cmd/main package yet to
+	// expose one — callers construct a ComponentCache with Offline set
+	// directly until that flag exists.
+	Offline bool
+
+	// shaLocks serializes concurrent Fetch calls that resolve to the same
+	// SHA, so two subcomponents (or two parallel generate runs) that miss
+	// the cache at the same time don't both clone into the same cachedPath.
+	// Keyed by sha, values are *sync.Mutex.
+	shaLocks sync.Map
+}
+
+// NewComponentCache returns a ComponentCache rooted at
+// $XDG_CACHE_HOME/fabrikate/components, falling back to
+// $HOME/.cache/fabrikate/components when XDG_CACHE_HOME is unset.
+func NewComponentCache() *ComponentCache {
+	return &ComponentCache{Dir: defaultCacheDir()}
+}
+
+func defaultCacheDir() string {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			base = path.Join(home, ".cache")
+		}
+	}
+
+	return path.Join(base, "fabrikate", "components")
+}
+
+// listRemoteRefs lists subcomponent.Source's refs in-memory, without
+// checking out a working tree. It's a package variable so tests can
+// substitute a fake and assert it was (or wasn't) called, without making a
+// real network call.
+var listRemoteRefs = func(subcomponent Component) ([]*plumbing.Reference, error) {
+	auth, err := subcomponent.Auth.transportAuth(subcomponent.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{subcomponent.Source},
+	})
+
+	// AppendPeeled adds a refs/tags/<tag>^{} entry alongside refs/tags/<tag>
+	// for annotated tags, pointing at the commit the tag annotates rather
+	// than the tag object itself. Without it, ResolveSHA would resolve an
+	// annotated tag to its tag-object SHA, which GoGitFetcher.Fetch cannot
+	// check out.
+	return remote.List(&git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+}
+
+// peeledReferenceName returns the dereferenced-tag form of name (e.g.
+// "refs/tags/v1.0.0^{}"), which go-git populates alongside name itself for
+// annotated tags when listing refs with PeelingOption: AppendPeeled.
+func peeledReferenceName(name plumbing.ReferenceName) plumbing.ReferenceName {
+	return plumbing.ReferenceName(name.String() + "^{}")
+}
+
+// ResolveSHA resolves subcomponent's pinned ref to a commit SHA. If
+// subcomponent.Commit is already set it's returned as-is; otherwise the
+// remote's tag, branch, or HEAD ref is listed in-memory (no working tree is
+// checked out) and matched to its commit.
+func ResolveSHA(subcomponent Component) (string, error) {
+	if subcomponent.Commit != "" {
+		return subcomponent.Commit, nil
+	}
+
+	refs, err := listRemoteRefs(subcomponent)
+	if err != nil {
+		return "", errors.Wrapf(err, "listing remote refs for %s", subcomponent.Source)
+	}
+
+	var want plumbing.ReferenceName
+	switch {
+	case subcomponent.Tag != "":
+		want = plumbing.NewTagReferenceName(subcomponent.Tag)
+	case subcomponent.Branch != "":
+		want = plumbing.NewBranchReferenceName(subcomponent.Branch)
+	default:
+		want = plumbing.HEAD
+	}
+
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, ref := range refs {
+		byName[ref.Name()] = ref
+	}
+
+	// An annotated tag's peeled entry (refs/tags/<tag>^{}) points straight
+	// at the commit it annotates; a lightweight tag has no peeled entry, so
+	// fall back to the tag ref itself.
+	target, ok := byName[peeledReferenceName(want)]
+	if !ok {
+		target, ok = byName[want]
+	}
+	if !ok {
+		return "", errors.Errorf("could not find ref %s for %s", want, subcomponent.Source)
+	}
+
+	// HEAD is a symbolic ref; follow it to the concrete ref it points at.
+	if target.Type() == plumbing.SymbolicReference {
+		target, ok = byName[target.Target()]
+		if !ok {
+			return "", errors.Errorf("could not resolve HEAD for %s", subcomponent.Source)
+		}
+	}
+
+	return target.Hash().String(), nil
+}
+
+// lockForSHA returns the mutex serializing Fetch calls for sha, creating one
+// on first use.
+func (cache *ComponentCache) lockForSHA(sha string) *sync.Mutex {
+	value, _ := cache.shaLocks.LoadOrStore(sha, &sync.Mutex{})
+	return value.(*sync.Mutex)
+}
+
+// Fetch populates destinationPath with subcomponent's tree, using fetcher to
+// do the actual clone only on a cache miss. On a hit, the cached tree at
+// Dir/<sha> is hard-linked (falling back to a copy across filesystems) into
+// destinationPath instead of re-cloning.
+func (cache *ComponentCache) Fetch(subcomponent Component, destinationPath string, fetcher GitFetcher) error {
+	// ResolveSHA only needs the network when subcomponent.Commit isn't
+	// already pinned (it lists the remote's branch/tag/HEAD refs). Offline
+	// mode must refuse that call outright rather than let it run and only
+	// complain once the result turns out to be an uncached miss.
+	if subcomponent.Commit == "" && cache.Offline {
+		return errors.Errorf("%s is not pinned to a commit; cannot resolve its ref without network access while offline", subcomponent.Source)
+	}
+
+	sha, err := ResolveSHA(subcomponent)
+	if err != nil {
+		return err
+	}
+
+	// Hold the per-sha lock across the whole hit-check/populate sequence so
+	// that two Fetch calls racing on the same sha don't both observe a miss
+	// and both clone into cachedPath at once.
+	lock := cache.lockForSHA(sha)
+	lock.Lock()
+	defer lock.Unlock()
+
+	cachedPath := path.Join(cache.Dir, sha)
+	if _, statErr := os.Stat(cachedPath); statErr == nil {
+		log.Debugf("cache hit for %s@%s, reusing %s", subcomponent.Source, sha, cachedPath)
+		return copyTree(cachedPath, destinationPath)
+	} else if !os.IsNotExist(statErr) {
+		return errors.Wrapf(statErr, "checking cache for %s", cachedPath)
+	}
+
+	if cache.Offline {
+		return errors.Errorf("%s@%s is not in the offline cache at %s", subcomponent.Source, sha, cachedPath)
+	}
+
+	pinned := subcomponent
+	pinned.Commit = sha
+	pinned.Branch = ""
+	pinned.Tag = ""
+
+	if err := fetcher.Fetch(pinned, cachedPath); err != nil {
+		return err
+	}
+
+	return copyTree(cachedPath, destinationPath)
+}
+
+// copyTree recreates src's directory tree at dst, hard-linking each file
+// where possible and falling back to a byte copy across filesystem
+// boundaries (hard links can't cross devices).
+func copyTree(src string, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		if err := os.Link(p, target); err == nil {
+			return nil
+		}
+
+		return copyFile(p, target, info.Mode())
+	})
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, source)
+	return err
+}
+
+// defaultComponentCache is the cache Component.Install fetches git
+// subcomponents through. A future `fabrikate generate --offline` flag would
+// set its Offline field before generation starts so that a cache miss fails
+// loudly instead of silently falling back to the network.
+var defaultComponentCache = NewComponentCache()
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFetcher is a GitFetcher fake that records how many times it was
+// invoked and writes a single marker file into destinationPath, simulating a
+// real clone without touching the network. Like git.PlainClone, it refuses
+// to clone into a destination that already exists, so two goroutines racing
+// into the same cachedPath without the cache's per-sha lock will surface as
+// an error from one of them instead of silently corrupting the tree.
+type countingFetcher struct {
+	calls int64
+}
+
+func (f *countingFetcher) Fetch(subcomponent Component, destinationPath string) error {
+	if _, err := os.Stat(destinationPath); err == nil {
+		return errors.New("destination already exists: " + destinationPath)
+	}
+
+	atomic.AddInt64(&f.calls, 1)
+	// Give a concurrent, unlocked Fetch call a window to observe the same
+	// miss and start cloning into destinationPath too.
+	time.Sleep(10 * time.Millisecond)
+
+	if err := os.MkdirAll(destinationPath, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(destinationPath, "marker.txt"), []byte(subcomponent.Commit), 0644)
+}
+
+func TestResolveSHAReturnsPinnedCommitWithoutNetwork(t *testing.T) {
+	sha, err := ResolveSHA(Component{Source: "https://example.invalid/repo.git", Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", sha)
+}
+
+func TestResolveSHAAnnotatedTagResolvesToCommitNotTagObject(t *testing.T) {
+	source, commit := initTestRepoWithAnnotatedTag(t, "v1.0.0")
+
+	sha, err := ResolveSHA(Component{Source: source, Tag: "v1.0.0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, commit.String(), sha, "an annotated tag must resolve to the commit it annotates, not the tag object")
+}
+
+func TestResolveSHALightweightTagResolvesToCommit(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	commit := commitReadme(t, repo, "hello")
+
+	_, err = repo.CreateTag("v1.0.0", commit, nil)
+	assert.NoError(t, err)
+
+	sha, err := ResolveSHA(Component{Source: "file://" + dir, Tag: "v1.0.0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, commit.String(), sha)
+}
+
+// initTestRepoWithAnnotatedTag creates a local repository with a single
+// commit and an annotated tag pointing at it, returning the repo's file://
+// URL and the commit it annotates (distinct from the tag object's own hash).
+func initTestRepoWithAnnotatedTag(t *testing.T, tag string) (source string, commit plumbing.Hash) {
+	t.Helper()
+	dir := t.TempDir()
+
+	repo, err := git.PlainInit(dir, false)
+	assert.NoError(t, err)
+	commit = commitReadme(t, repo, "hello")
+
+	tagger := &object.Signature{Name: "test", Email: "test@example.invalid", When: time.Unix(0, 0)}
+	_, err = repo.CreateTag(tag, commit, &git.CreateTagOptions{Message: tag, Tagger: tagger})
+	assert.NoError(t, err)
+
+	return "file://" + dir, commit
+}
+
+// commitReadme writes contents to README.md in repo's worktree and commits
+// it, returning the new commit's hash.
+func commitReadme(t *testing.T, repo *git.Repository, contents string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(worktree.Filesystem.Root(), "README.md"), []byte(contents), 0644))
+	_, err = worktree.Add("README.md")
+	assert.NoError(t, err)
+
+	author := &object.Signature{Name: "test", Email: "test@example.invalid", When: time.Unix(0, 0)}
+	hash, err := worktree.Commit(contents, &git.CommitOptions{Author: author})
+	assert.NoError(t, err)
+	return hash
+}
+
+func TestComponentCacheFetchPopulatesOnMissAndReusesOnHit(t *testing.T) {
+	cache := &ComponentCache{Dir: t.TempDir()}
+	fetcher := &countingFetcher{}
+	subcomponent := Component{Name: "widget", Source: "https://example.invalid/widget.git", Commit: "cafecafecafecafecafecafecafecafecafecafe"}
+
+	destinationA := filepath.Join(t.TempDir(), "a")
+	assert.NoError(t, cache.Fetch(subcomponent, destinationA, fetcher))
+	assert.FileExists(t, filepath.Join(destinationA, "marker.txt"))
+	assert.EqualValues(t, 1, fetcher.calls)
+
+	destinationB := filepath.Join(t.TempDir(), "b")
+	assert.NoError(t, cache.Fetch(subcomponent, destinationB, fetcher))
+	assert.FileExists(t, filepath.Join(destinationB, "marker.txt"))
+	assert.EqualValues(t, 1, fetcher.calls, "second fetch of the same sha should be served from cache")
+}
+
+func TestComponentCacheFetchOfflineMissErrors(t *testing.T) {
+	cache := &ComponentCache{Dir: t.TempDir(), Offline: true}
+	fetcher := &countingFetcher{}
+	subcomponent := Component{Name: "widget", Source: "https://example.invalid/widget.git", Commit: "babebabebabebabebabebabebabebabebabebabe"}
+
+	err := cache.Fetch(subcomponent, filepath.Join(t.TempDir(), "dest"), fetcher)
+
+	assert.Error(t, err)
+	assert.EqualValues(t, 0, fetcher.calls, "offline mode must never fall through to the network on a miss")
+}
+
+func TestComponentCacheFetchOfflineRefusesUnpinnedRefResolution(t *testing.T) {
+	var listCalls int64
+	originalListRemoteRefs := listRemoteRefs
+	listRemoteRefs = func(subcomponent Component) ([]*plumbing.Reference, error) {
+		atomic.AddInt64(&listCalls, 1)
+		return originalListRemoteRefs(subcomponent)
+	}
+	defer func() { listRemoteRefs = originalListRemoteRefs }()
+
+	cache := &ComponentCache{Dir: t.TempDir(), Offline: true}
+	fetcher := &countingFetcher{}
+	// No Commit set, so resolving this ref would normally require listing
+	// the remote's refs over the network.
+	subcomponent := Component{Name: "widget", Source: "https://example.invalid/widget.git", Branch: "main"}
+
+	err := cache.Fetch(subcomponent, filepath.Join(t.TempDir(), "dest"), fetcher)
+
+	assert.Error(t, err)
+	assert.EqualValues(t, 0, fetcher.calls)
+	assert.EqualValues(t, 0, listCalls, "offline mode must refuse before ever listing the remote's refs")
+}
+
+func TestComponentCacheFetchConcurrentSameSHAOnlyFetchesOnce(t *testing.T) {
+	cache := &ComponentCache{Dir: t.TempDir()}
+	fetcher := &countingFetcher{}
+	subcomponent := Component{Name: "widget", Source: "https://example.invalid/widget.git", Commit: "1234567890123456789012345678901234567890"}
+
+	const concurrency = 16
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			destination := filepath.Join(t.TempDir(), fmt.Sprintf("dest-%d", i))
+			errs[i] = cache.Fetch(subcomponent, destination, fetcher)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, fetcher.calls, "concurrent fetches of the same sha must not race into the same cache entry")
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeComponent writes a minimal component.json at dir/component.json, creating dir
+// if needed.
+func writeComponent(t *testing.T, dir string, contents string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "component.json"), []byte(contents), 0644))
+}
+
+// buildFanOutTree creates a root component with fanOut git subcomponents,
+// each already "installed" under components/<name>, and returns the root dir.
+func buildFanOutTree(t *testing.T, fanOut int) string {
+	t.Helper()
+	root := t.TempDir()
+
+	subcomponents := make([]string, 0, fanOut)
+	for i := 0; i < fanOut; i++ {
+		name := fmt.Sprintf("child-%d", i)
+		subcomponents = append(subcomponents, fmt.Sprintf(`{"name":%q,"source":"inline-%s","method":"git"}`, name, name))
+		writeComponent(t, filepath.Join(root, "components", name), fmt.Sprintf(`{"name":%q}`, name))
+	}
+
+	writeComponent(t, root, fmt.Sprintf(`{"name":"root","subcomponents":[%s]}`, joinJSON(subcomponents)))
+
+	return root
+}
+
+func joinJSON(entries []string) string {
+	out := ""
+	for i, entry := range entries {
+		if i > 0 {
+			out += ","
+		}
+		out += entry
+	}
+	return out
+}
+
+func TestIterateComponentTreeOrdering(t *testing.T) {
+	root := buildFanOutTree(t, 8)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+
+	components, err := IterateComponentTree(root, "common", func(p string, c *Component) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[c.LogicalPath] = true
+		return nil
+	}, IterateOptions{Concurrency: 4})
+
+	assert.NoError(t, err)
+	assert.Len(t, components, 9) // root + 8 children
+	assert.Len(t, seen, 9)
+
+	for i := 1; i < len(components); i++ {
+		assert.LessOrEqual(t, components[i-1].LogicalPath, components[i].LogicalPath, "completedComponents must be sorted by LogicalPath")
+	}
+}
+
+func TestIterateComponentTreePropagatesError(t *testing.T) {
+	root := buildFanOutTree(t, 8)
+
+	expected := errors.New("boom")
+	_, err := IterateComponentTree(root, "common", func(p string, c *Component) error {
+		if c.Name == "child-3" {
+			return expected
+		}
+		return nil
+	}, IterateOptions{Concurrency: 4})
+
+	assert.Error(t, err)
+}
+
+func TestIterateComponentTreeConcurrentCallbackIsRaceFree(t *testing.T) {
+	root := buildFanOutTree(t, 32)
+
+	var mu sync.Mutex
+	count := 0
+
+	_, err := IterateComponentTree(root, "common", func(p string, c *Component) error {
+		mu.Lock()
+		count++
+		mu.Unlock()
+		return nil
+	}, IterateOptions{Concurrency: 8})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 33, count) // root + 32 children
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeEnvConfig writes a config/<env>.json file under root containing the
+// given raw JSON body.
+func writeEnvConfig(t *testing.T, root string, env string, body string) {
+	t.Helper()
+	configDir := filepath.Join(root, "config")
+	assert.NoError(t, os.MkdirAll(configDir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(configDir, env+".json"), []byte(body), 0644))
+}
+
+func TestLoadConfigMergesChainMostSpecificFirst(t *testing.T) {
+	root := t.TempDir()
+	writeEnvConfig(t, root, "common", `{"config":{"region":"us","tier":"common"}}`)
+	writeEnvConfig(t, root, "staging", `{"config":{"tier":"staging"}}`)
+	writeEnvConfig(t, root, "prod", `{"config":{"tier":"prod","replicas":3}}`)
+
+	c := &Component{
+		PhysicalPath: root,
+		Environments: map[string]EnvironmentSpec{
+			"prod":    {Inherits: []string{"staging"}},
+			"staging": {Inherits: []string{"common"}},
+		},
+	}
+
+	assert.NoError(t, c.LoadConfig("prod"))
+
+	assert.Equal(t, "prod", c.Config.Config["tier"], "the most specific layer's value must win")
+	assert.Equal(t, "us", c.Config.Config["region"], "a value only set by an ancestor must still be filled in")
+	assert.EqualValues(t, 3, c.Config.Config["replicas"])
+}
+
+func TestLoadConfigFallsBackToCommonWhenNotInChain(t *testing.T) {
+	root := t.TempDir()
+	writeEnvConfig(t, root, "common", `{"config":{"region":"us"}}`)
+	writeEnvConfig(t, root, "prod", `{"config":{"tier":"prod"}}`)
+
+	c := &Component{PhysicalPath: root}
+
+	assert.NoError(t, c.LoadConfig("prod"))
+
+	assert.Equal(t, "prod", c.Config.Config["tier"])
+	assert.Equal(t, "us", c.Config.Config["region"], "common.json must be consulted even when no chain declares it")
+}
+
+func TestLoadConfigDoesNotDoubleApplyCommonWhenChainDeclaresIt(t *testing.T) {
+	root := t.TempDir()
+	writeEnvConfig(t, root, "common", `{"config":{"region":"us"}}`)
+	writeEnvConfig(t, root, "prod", `{"config":{"tier":"prod"}}`)
+
+	c := &Component{
+		PhysicalPath: root,
+		Environments: map[string]EnvironmentSpec{
+			"prod": {Inherits: []string{"common"}},
+		},
+	}
+
+	assert.NoError(t, c.LoadConfig("prod"))
+
+	assert.Equal(t, "prod", c.Config.Config["tier"])
+	assert.Equal(t, "us", c.Config.Config["region"])
+}
+
+func TestLoadConfigUsesFileBasedInheritsConvention(t *testing.T) {
+	root := t.TempDir()
+	// No Environments declared in component.json; "prod" instead declares
+	// its parent via the "inherits" key inside its own config file.
+	writeEnvConfig(t, root, "common", `{"config":{"region":"us"}}`)
+	writeEnvConfig(t, root, "staging", `{"inherits":["common"],"config":{"tier":"staging"}}`)
+	writeEnvConfig(t, root, "prod", `{"inherits":["staging"],"config":{"tier":"prod","replicas":3}}`)
+
+	c := &Component{PhysicalPath: root}
+
+	assert.NoError(t, c.LoadConfig("prod"))
+
+	assert.Equal(t, "prod", c.Config.Config["tier"])
+	assert.Equal(t, "us", c.Config.Config["region"])
+	assert.EqualValues(t, 3, c.Config.Config["replicas"])
+}
+
+func TestLoadConfigDeclaredEnvironmentsTakePrecedenceOverFileInherits(t *testing.T) {
+	root := t.TempDir()
+	writeEnvConfig(t, root, "common", `{"config":{"region":"us"}}`)
+	// prod.json's own "inherits" key names "staging", but component.json's
+	// Environments declaration names "common" directly; the declaration
+	// wins, so "staging" (which doesn't even exist on disk) is never
+	// consulted.
+	writeEnvConfig(t, root, "prod", `{"inherits":["staging"],"config":{"tier":"prod"}}`)
+
+	c := &Component{
+		PhysicalPath: root,
+		Environments: map[string]EnvironmentSpec{
+			"prod": {Inherits: []string{"common"}},
+		},
+	}
+
+	assert.NoError(t, c.LoadConfig("prod"))
+
+	assert.Equal(t, "prod", c.Config.Config["tier"])
+	assert.Equal(t, "us", c.Config.Config["region"])
+}
@@ -0,0 +1,40 @@
+package core
+
+// ComponentConfig holds the config overrides for a single component, keyed
+// arbitrarily under Config, plus overrides for each of its subcomponents
+// keyed by name under Subcomponents. It's the shape every config/*.json
+// file is unmarshalled into, and what MergeConfigFile/LoadConfig layer
+// together across an environment's inheritance chain.
+type ComponentConfig struct {
+	Config        map[string]interface{}     `json:"config"`
+	Subcomponents map[string]ComponentConfig `json:"subcomponents"`
+}
+
+// Merge folds mergeConfig into c, keeping c's existing values wherever a key
+// is already set. Callers rely on this fill-only behavior to apply config
+// layers most-specific first, so later merges only backfill keys an earlier,
+// more specific layer didn't already provide.
+func (c *ComponentConfig) Merge(mergeConfig ComponentConfig) {
+	if c.Config == nil {
+		c.Config = make(map[string]interface{})
+	}
+
+	for key, value := range mergeConfig.Config {
+		if _, exists := c.Config[key]; !exists {
+			c.Config[key] = value
+		}
+	}
+
+	if c.Subcomponents == nil {
+		c.Subcomponents = make(map[string]ComponentConfig)
+	}
+
+	for name, subConfig := range mergeConfig.Subcomponents {
+		if existing, exists := c.Subcomponents[name]; exists {
+			existing.Merge(subConfig)
+			c.Subcomponents[name] = existing
+		} else {
+			c.Subcomponents[name] = subConfig
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// EnvironmentSpec declares how a named environment relates to others, e.g.
+//
+//	"environments": {"prod": {"inherits": ["staging"]}}
+type EnvironmentSpec struct {
+	Inherits []string `json:"inherits"`
+}
+
+// environmentInheritsFile is used to pull just the "inherits" key out of an
+// environment's own config file, e.g. config/prod.json containing a
+// top-level {"inherits": ["staging"]}. It's read independently of
+// ComponentConfig so that key is never confused with ordinary config data.
+type environmentInheritsFile struct {
+	Inherits []string `json:"inherits"`
+}
+
+// resolveEnvironmentChain returns the environments environment depends on,
+// ordered from most-general to most-specific, with environment itself last.
+// Parents are declared either in c.Environments (component.json) or via the
+// "inherits" key inside the environment's own config/<env>.json file; the
+// former takes precedence when both are present. A cycle anywhere in the
+// chain is reported as an error.
+func (c *Component) resolveEnvironmentChain(environment string) ([]string, error) {
+	order := make([]string, 0)
+	visiting := make(map[string]bool)
+	resolved := make(map[string]bool)
+
+	if err := c.walkEnvironmentChain(environment, visiting, resolved, &order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// environmentConfigPaths returns the on-disk config file for every
+// environment in c's inheritance chain for environment, e.g. for a chain of
+// [common, staging, prod] it returns config/common.json, config/staging.json,
+// and config/prod.json. PruneComponentTree uses this so an orphaned
+// subcomponent's key is stripped out of every ancestor config, not just the
+// two-level common+env pair a fixed-depth chain would assume. common.json is
+// appended when it isn't already part of the chain, mirroring LoadConfig's
+// implicit fallback.
+func (c *Component) environmentConfigPaths(environment string) ([]string, error) {
+	chain, err := c.resolveEnvironmentChain(environment)
+	if err != nil {
+		return nil, err
+	}
+
+	hasCommon := false
+	paths := make([]string, 0, len(chain)+1)
+	for _, env := range chain {
+		if env == "common" {
+			hasCommon = true
+		}
+		paths = append(paths, path.Join(c.PhysicalPath, "config", fmt.Sprintf("%s.json", env)))
+	}
+
+	if !hasCommon {
+		paths = append(paths, path.Join(c.PhysicalPath, "config", "common.json"))
+	}
+
+	return paths, nil
+}
+
+func (c *Component) walkEnvironmentChain(environment string, visiting map[string]bool, resolved map[string]bool, order *[]string) error {
+	if resolved[environment] {
+		return nil
+	}
+
+	if visiting[environment] {
+		return errors.Errorf("cycle detected in environment inheritance chain at %s", environment)
+	}
+	visiting[environment] = true
+
+	parents, err := c.environmentParents(environment)
+	if err != nil {
+		return err
+	}
+
+	for _, parent := range parents {
+		if err := c.walkEnvironmentChain(parent, visiting, resolved, order); err != nil {
+			return err
+		}
+	}
+
+	visiting[environment] = false
+	resolved[environment] = true
+	*order = append(*order, environment)
+
+	return nil
+}
+
+// environmentParents returns the environments that environment directly
+// inherits from, checking c.Environments first and falling back to the
+// "inherits" key inside config/<environment>.json.
+func (c *Component) environmentParents(environment string) ([]string, error) {
+	if spec, ok := c.Environments[environment]; ok {
+		return spec.Inherits, nil
+	}
+
+	envConfigPath := path.Join(c.PhysicalPath, "config", fmt.Sprintf("%s.json", environment))
+	if _, err := os.Stat(envConfigPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(envConfigPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", envConfigPath)
+	}
+
+	var parsed environmentInheritsFile
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, errors.Wrapf(err, "parsing inherits from %s", envConfigPath)
+	}
+
+	return parsed.Inherits, nil
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEnvironmentChainInheritanceOrder(t *testing.T) {
+	c := &Component{
+		Environments: map[string]EnvironmentSpec{
+			"prod":    {Inherits: []string{"staging"}},
+			"staging": {Inherits: []string{"common"}},
+		},
+	}
+
+	chain, err := c.resolveEnvironmentChain("prod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"common", "staging", "prod"}, chain)
+}
+
+func TestResolveEnvironmentChainDetectsCycle(t *testing.T) {
+	c := &Component{
+		Environments: map[string]EnvironmentSpec{
+			"a": {Inherits: []string{"b"}},
+			"b": {Inherits: []string{"a"}},
+		},
+	}
+
+	_, err := c.resolveEnvironmentChain("a")
+
+	assert.Error(t, err)
+}
+
+func TestResolveEnvironmentChainNoInheritance(t *testing.T) {
+	c := &Component{PhysicalPath: t.TempDir()}
+
+	chain, err := c.resolveEnvironmentChain("prod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, chain)
+}
+
+func TestEnvironmentConfigPathsIncludesCommonFallback(t *testing.T) {
+	c := &Component{
+		PhysicalPath: "/stacks/example",
+		Environments: map[string]EnvironmentSpec{
+			"prod": {Inherits: []string{"staging"}},
+		},
+	}
+
+	paths, err := c.environmentConfigPaths("prod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/stacks/example/config/staging.json",
+		"/stacks/example/config/prod.json",
+		"/stacks/example/config/common.json",
+	}, paths)
+}
+
+func TestEnvironmentConfigPathsDoesNotDuplicateCommon(t *testing.T) {
+	c := &Component{
+		PhysicalPath: "/stacks/example",
+		Environments: map[string]EnvironmentSpec{
+			"staging": {Inherits: []string{"common"}},
+			"prod":    {Inherits: []string{"staging"}},
+		},
+	}
+
+	paths, err := c.environmentConfigPaths("prod")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/stacks/example/config/common.json",
+		"/stacks/example/config/staging.json",
+		"/stacks/example/config/prod.json",
+	}, paths)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// GitAuth describes how to authenticate against a remote git source. At most
+// one of the fields below should be populated; they are resolved in the
+// order Token, Username/Password, SSHKeyPath.
+type GitAuth struct {
+	Username   string
+	Password   string
+	Token      string
+	SSHKeyPath string
+}
+
+// transportAuth resolves a GitAuth into a go-git transport.AuthMethod,
+// falling back to environment variables when the fields are unset so that
+// component.json never needs to carry credentials directly.
+func (a GitAuth) transportAuth(source string) (transport.AuthMethod, error) {
+	token := a.Token
+	if token == "" {
+		token = os.Getenv("FABRIKATE_GIT_TOKEN")
+	}
+	username := a.Username
+	if username == "" {
+		username = os.Getenv("FABRIKATE_GIT_USERNAME")
+	}
+	password := a.Password
+	if password == "" {
+		password = os.Getenv("FABRIKATE_GIT_PASSWORD")
+	}
+	sshKeyPath := a.SSHKeyPath
+	if sshKeyPath == "" {
+		sshKeyPath = os.Getenv("FABRIKATE_GIT_SSH_KEY")
+	}
+
+	switch {
+	case token != "":
+		return &githttp.BasicAuth{Username: "token", Password: token}, nil
+	case username != "" || password != "":
+		return &githttp.BasicAuth{Username: username, Password: password}, nil
+	case sshKeyPath != "":
+		auth, err := gitssh.NewPublicKeysFromFile("git", sshKeyPath, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading ssh key from %s for %s", sshKeyPath, source)
+		}
+		return auth, nil
+	default:
+		return nil, nil
+	}
+}
+
+// GitFetcher fetches a subcomponent's source tree into destinationPath.
+// Implementations are responsible for selecting the right ref (branch, tag,
+// or commit). They do not clear destinationPath first: Install removes it,
+// and ComponentCache.Fetch only calls Fetch for a cache path that doesn't
+// exist yet.
+type GitFetcher interface {
+	Fetch(subcomponent Component, destinationPath string) error
+}
+
+// GoGitFetcher is the default GitFetcher, implemented with go-git so that
+// fabrikate never has to shell out to a system git binary.
+type GoGitFetcher struct{}
+
+// Fetch clones subcomponent.Source into destinationPath using go-git,
+// checking out subcomponent.Commit, subcomponent.Tag, or subcomponent.Branch
+// (in that order of precedence) and recursing into submodules.
+func (f GoGitFetcher) Fetch(subcomponent Component, destinationPath string) error {
+	auth, err := subcomponent.Auth.transportAuth(subcomponent.Source)
+	if err != nil {
+		return err
+	}
+
+	cloneOptions := &git.CloneOptions{
+		URL:               subcomponent.Source,
+		Auth:              auth,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	}
+
+	// A shallow clone of the branch, tag, or default HEAD is the fast path; pinning to a specific commit
+	// requires a full clone since go-git cannot shallow-fetch an arbitrary SHA.
+	if subcomponent.Commit == "" {
+		cloneOptions.Depth = 1
+	}
+
+	switch {
+	case subcomponent.Tag != "":
+		cloneOptions.ReferenceName = plumbing.NewTagReferenceName(subcomponent.Tag)
+		cloneOptions.SingleBranch = true
+	case subcomponent.Branch != "":
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(subcomponent.Branch)
+		cloneOptions.SingleBranch = true
+	}
+
+	log.Debugf("cloning component '%s' from '%s' into '%s'", subcomponent.Name, subcomponent.Source, destinationPath)
+	repo, err := git.PlainClone(destinationPath, false, cloneOptions)
+	if err != nil {
+		return errors.Wrapf(err, "git clone of %s failed", subcomponent.Source)
+	}
+
+	if subcomponent.Commit != "" {
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return errors.Wrapf(err, "opening worktree for %s", subcomponent.Source)
+		}
+
+		if err := worktree.Checkout(&git.CheckoutOptions{
+			Hash: plumbing.NewHash(subcomponent.Commit),
+		}); err != nil {
+			return errors.Wrapf(err, "checking out commit %s for %s", subcomponent.Commit, subcomponent.Source)
+		}
+	}
+
+	return nil
+}
+
+// defaultGitFetcher is the GitFetcher used by Component.Install. Tests may
+// swap it out for a fake to avoid making network calls.
+var defaultGitFetcher GitFetcher = GoGitFetcher{}
+
+// ref returns the most specific ref the subcomponent pins to, for logging.
+func ref(subcomponent Component) string {
+	switch {
+	case subcomponent.Commit != "":
+		return subcomponent.Commit
+	case subcomponent.Tag != "":
+		return subcomponent.Tag
+	case subcomponent.Branch != "":
+		return subcomponent.Branch
+	default:
+		return "HEAD"
+	}
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kyokomi/emoji"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// httpClient is the http.Client used to fetch "http"/"https" sourced
+// components. It is a package variable so tests can point it at a fake
+// transport without needing a real server.
+var httpClient = &http.Client{
+	Timeout: 60 * time.Second,
+}
+
+// fetchHTTPComponent downloads subcomponent.Source, optionally verifying it
+// against subcomponent.SHA256, and extracts the archive into destinationPath.
+// Both zip and tar.gz archives are supported, inferred from the source's
+// file extension.
+func fetchHTTPComponent(subcomponent Component, destinationPath string) error {
+	req, err := http.NewRequest(http.MethodGet, subcomponent.Source, nil)
+	if err != nil {
+		return errors.Wrapf(err, "building request for %s", subcomponent.Source)
+	}
+
+	if token := os.Getenv("FABRIKATE_HTTP_TOKEN"); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	log.Println(emojiFetching(subcomponent))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "downloading %s", subcomponent.Source)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("downloading %s: unexpected status %s", subcomponent.Source, resp.Status)
+	}
+
+	sourcePath := archiveSourcePath(subcomponent.Source)
+
+	archivePath := filepath.Join(os.TempDir(), fmt.Sprintf("fabrikate-%s-%d%s", subcomponent.Name, time.Now().UnixNano(), filepath.Ext(sourcePath)))
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "creating temp archive for %s", subcomponent.Source)
+	}
+	defer os.Remove(archivePath)
+	defer archiveFile.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archiveFile, hasher), resp.Body); err != nil {
+		return errors.Wrapf(err, "writing %s to disk", subcomponent.Source)
+	}
+
+	if subcomponent.SHA256 != "" {
+		actual := hex.EncodeToString(hasher.Sum(nil))
+		if !strings.EqualFold(actual, subcomponent.SHA256) {
+			return errors.Errorf("sha256 mismatch for %s: expected %s, got %s", subcomponent.Source, subcomponent.SHA256, actual)
+		}
+	}
+
+	if err := os.MkdirAll(destinationPath, 0755); err != nil {
+		return errors.Wrapf(err, "creating destination %s", destinationPath)
+	}
+
+	switch {
+	case strings.HasSuffix(sourcePath, ".zip"):
+		return extractZip(archivePath, destinationPath)
+	case strings.HasSuffix(sourcePath, ".tar.gz"), strings.HasSuffix(sourcePath, ".tgz"):
+		return extractTarGz(archivePath, destinationPath)
+	default:
+		return errors.Errorf("unsupported archive format for %s: expected .zip, .tar.gz, or .tgz", subcomponent.Source)
+	}
+}
+
+func emojiFetching(subcomponent Component) string {
+	return emoji.Sprintf(":satellite: installing component %s with http from %s", subcomponent.Name, subcomponent.Source)
+}
+
+// archiveSourcePath returns the path component of source, stripping any
+// query string or fragment (e.g. a signed URL's "?X-Amz-Signature=...") so
+// archive-format detection matches on the actual file name rather than
+// incidentally matching or failing against query parameters. If source
+// isn't a valid URL, it's returned as-is.
+func archiveSourcePath(source string) string {
+	u, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	return u.Path
+}
+
+// safeJoin joins destinationPath with an archive entry's name, rejecting
+// entries (e.g. "../../etc/passwd") whose cleaned path would escape
+// destinationPath. This guards http-sourced archives, which may come from a
+// compromised or tampered release, against Zip Slip style path traversal.
+func safeJoin(destinationPath string, entryName string) (string, error) {
+	targetPath := filepath.Join(destinationPath, entryName)
+
+	destinationRoot := filepath.Clean(destinationPath) + string(os.PathSeparator)
+	if !strings.HasPrefix(filepath.Clean(targetPath)+string(os.PathSeparator), destinationRoot) {
+		return "", errors.Errorf("archive entry %q escapes destination %s", entryName, destinationPath)
+	}
+
+	return targetPath, nil
+}
+
+func extractZip(archivePath, destinationPath string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "opening zip archive %s", archivePath)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		targetPath, err := safeJoin(destinationPath, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(targetPath, file.Mode()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+
+		if err := extractZipFile(file, targetPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractZipFile(file *zip.File, targetPath string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return errors.Wrapf(err, "reading %s from zip", file.Name)
+	}
+	defer reader.Close()
+
+	target, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		return errors.Wrapf(err, "creating %s", targetPath)
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, reader)
+	return err
+}
+
+func extractTarGz(archivePath, destinationPath string) error {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "opening archive %s", archivePath)
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return errors.Wrapf(err, "reading gzip stream from %s", archivePath)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "reading tar entry from %s", archivePath)
+		}
+
+		targetPath, err := safeJoin(destinationPath, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(targetPath, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+				return err
+			}
+			target, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return errors.Wrapf(err, "creating %s", targetPath)
+			}
+			if _, err := io.Copy(target, tarReader); err != nil {
+				target.Close()
+				return err
+			}
+			target.Close()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveSourcePathStripsQueryString(t *testing.T) {
+	assert.Equal(t, "/widget.tar.gz", archiveSourcePath("https://example.invalid/widget.tar.gz?X-Amz-Signature=abc&X-Amz-Expires=900"))
+	assert.Equal(t, "/widget.zip", archiveSourcePath("https://example.invalid/widget.zip"))
+}
+
+// zipArchiveBytes builds an in-memory zip archive containing a single
+// entry, name, with the given contents.
+func zipArchiveBytes(t *testing.T, name, contents string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	entry, err := writer.Create(name)
+	assert.NoError(t, err)
+	_, err = entry.Write([]byte(contents))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestFetchHTTPComponentDownloadsAndExtractsSignedURL(t *testing.T) {
+	archive := zipArchiveBytes(t, "README.md", "hello from the archive")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "X-Amz-Signature=abc", r.URL.RawQuery)
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "dest")
+	subcomponent := Component{Name: "widget", Source: server.URL + "/widget.zip?X-Amz-Signature=abc"}
+
+	err := fetchHTTPComponent(subcomponent, destination)
+
+	assert.NoError(t, err)
+	contents, readErr := ioutil.ReadFile(filepath.Join(destination, "README.md"))
+	assert.NoError(t, readErr)
+	assert.Equal(t, "hello from the archive", string(contents))
+}
+
+func TestFetchHTTPComponentRejectsSHA256Mismatch(t *testing.T) {
+	archive := zipArchiveBytes(t, "README.md", "hello from the archive")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "dest")
+	subcomponent := Component{
+		Name:   "widget",
+		Source: server.URL + "/widget.zip",
+		SHA256: "0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	err := fetchHTTPComponent(subcomponent, destination)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sha256 mismatch")
+	_, statErr := os.Stat(filepath.Join(destination, "README.md"))
+	assert.True(t, os.IsNotExist(statErr), "a sha256 mismatch must not leave extracted files behind")
+}
+
+func TestSafeJoinRejectsPathTraversal(t *testing.T) {
+	destination := "/tmp/fabrikate-safejoin-dest"
+
+	_, err := safeJoin(destination, "../../etc/passwd")
+	assert.Error(t, err)
+
+	_, err = safeJoin(destination, "nested/../../escape.txt")
+	assert.Error(t, err)
+
+	target, err := safeJoin(destination, "nested/file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(destination, "nested", "file.txt"), target)
+}
+
+func TestExtractZipRejectsPathTraversal(t *testing.T) {
+	destination, err := ioutil.TempDir("", "fabrikate-zip-dest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(destination)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	entry, err := writer.Create("../escaped.txt")
+	assert.NoError(t, err)
+	_, err = entry.Write([]byte("pwned"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	archivePath := filepath.Join(destination, "archive.zip")
+	assert.NoError(t, ioutil.WriteFile(archivePath, buf.Bytes(), 0644))
+
+	err = extractZip(archivePath, filepath.Join(destination, "extracted"))
+	assert.Error(t, err)
+
+	_, statErr := os.Stat(filepath.Join(destination, "escaped.txt"))
+	assert.True(t, os.IsNotExist(statErr), "escaped.txt should not have been written outside the destination")
+}
+
+func TestExtractTarGzRejectsPathTraversal(t *testing.T) {
+	destination, err := ioutil.TempDir("", "fabrikate-targz-dest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(destination)
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name: "../escaped.txt",
+		Mode: 0644,
+		Size: int64(len("pwned")),
+	}))
+	_, err = tarWriter.Write([]byte("pwned"))
+	assert.NoError(t, err)
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+
+	archivePath := filepath.Join(destination, "archive.tar.gz")
+	assert.NoError(t, ioutil.WriteFile(archivePath, buf.Bytes(), 0644))
+
+	err = extractTarGz(archivePath, filepath.Join(destination, "extracted"))
+	assert.Error(t, err)
+
+	_, statErr := os.Stat(filepath.Join(destination, "escaped.txt"))
+	assert.True(t, os.IsNotExist(statErr), "escaped.txt should not have been written outside the destination")
+}
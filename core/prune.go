@@ -0,0 +1,215 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/kyokomi/emoji"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delete removes the named subcomponent from c, deletes its installed
+// directory (wherever RelativePathTo places it — components/<name> for a
+// git/http subcomponent, <name> directly for a plain Source-only one), and
+// strips its entry out of c.Config.Subcomponents so stale overlay config
+// doesn't linger.
+func (c *Component) Delete(name string) error {
+	remaining := make([]Component, 0, len(c.Subcomponents))
+	var match Component
+	found := false
+	for _, subcomponent := range c.Subcomponents {
+		if subcomponent.Name == name {
+			found = true
+			match = subcomponent
+			continue
+		}
+		remaining = append(remaining, subcomponent)
+	}
+
+	if !found {
+		return errors.Errorf("component %s has no subcomponent named %s", c.Name, name)
+	}
+
+	c.Subcomponents = remaining
+	delete(c.Config.Subcomponents, name)
+
+	// An inline subcomponent (no Source) has no installed directory of its
+	// own — RelativePathTo returns "./", meaning c.PhysicalPath itself.
+	// Removing that would delete the calling component's own component.json
+	// and everything alongside it, so there's nothing on disk to clean up.
+	if match.Source == "" {
+		return nil
+	}
+
+	installedPath := path.Join(c.PhysicalPath, match.RelativePathTo())
+	if err := os.RemoveAll(installedPath); err != nil {
+		return errors.Wrapf(err, "removing installed component directory %s", installedPath)
+	}
+
+	return nil
+}
+
+// PruneAction describes a single orphaned subcomponent directory that
+// PruneComponentTree plans to remove, along with the config files of the
+// parent's environment chain whose Subcomponents entry for that name will be
+// stripped (files that don't exist or lack the entry are left untouched).
+type PruneAction struct {
+	Name         string
+	PhysicalPath string
+	ConfigPaths  []string
+}
+
+// PrunePlan is the result of a PruneComponentTree dry run. Nothing on disk
+// is touched until it is passed to Apply.
+type PrunePlan struct {
+	Actions []PruneAction
+}
+
+// Apply executes a PrunePlan: removing each orphaned directory and rewriting
+// the config files that referenced it with the orphan key stripped out.
+func (p PrunePlan) Apply() error {
+	for _, action := range p.Actions {
+		log.Println(emoji.Sprintf(":wastebasket: pruning orphaned component %s at %s", action.Name, action.PhysicalPath))
+		if err := os.RemoveAll(action.PhysicalPath); err != nil {
+			return errors.Wrapf(err, "removing orphaned component directory %s", action.PhysicalPath)
+		}
+
+		for _, configPath := range action.ConfigPaths {
+			if err := stripSubcomponentKey(configPath, action.Name); err != nil {
+				return errors.Wrapf(err, "stripping orphan key %s from %s", action.Name, configPath)
+			}
+		}
+	}
+
+	return nil
+}
+
+// stripSubcomponentKey removes name from the "subcomponents" map of the
+// config file at configPath, leaving the file untouched if it doesn't exist
+// or doesn't reference name.
+func stripSubcomponentKey(configPath string, name string) error {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	raw, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	var config ComponentConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		return err
+	}
+
+	if config.Subcomponents == nil {
+		return nil
+	}
+
+	if _, ok := config.Subcomponents[name]; !ok {
+		return nil
+	}
+
+	delete(config.Subcomponents, name)
+
+	updated, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(configPath, updated, 0644)
+}
+
+// writeFileAtomic replaces path's contents by writing to a temp file in the
+// same directory and renaming it over path, rather than truncating path in
+// place. path may be one of several hard links into ComponentCache's shared,
+// content-addressed cache entries (see copyTree); an in-place truncate would
+// mutate the same inode the cache still points at, corrupting it for every
+// other consumer of that commit SHA. Renaming instead replaces the
+// destination's directory entry with a new inode, leaving the cache's link
+// untouched.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// PruneComponentTree walks the installed component tree rooted at
+// startingPath and produces a PrunePlan of subcomponent directories under
+// components/ that are no longer referenced by any parent's component.json.
+// This is the dry-run phase; callers apply the returned plan explicitly via
+// PrunePlan.Apply to actually delete anything, so pruning is always a
+// two-step, inspectable operation.
+func PruneComponentTree(startingPath string, environment string) (PrunePlan, error) {
+	referenced := make(map[string]bool)
+	var referencedMu sync.Mutex
+
+	components, err := IterateComponentTree(startingPath, environment, func(componentPath string, component *Component) error {
+		referencedMu.Lock()
+		referenced[componentPath] = true
+		referencedMu.Unlock()
+		return nil
+	}, IterateOptions{})
+	if err != nil {
+		return PrunePlan{}, errors.Wrap(err, "walking installed component tree")
+	}
+
+	plan := PrunePlan{}
+	for _, component := range components {
+		installedComponentsDir := path.Join(component.PhysicalPath, "components")
+		entries, err := ioutil.ReadDir(installedComponentsDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return PrunePlan{}, errors.Wrapf(err, "reading installed components directory %s", installedComponentsDir)
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		configPaths, err := component.environmentConfigPaths(environment)
+		if err != nil {
+			return PrunePlan{}, errors.Wrapf(err, "resolving environment chain for %s", component.PhysicalPath)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			childPath := path.Join(installedComponentsDir, entry.Name())
+			if referenced[childPath] {
+				continue
+			}
+
+			plan.Actions = append(plan.Actions, PruneAction{
+				Name:         entry.Name(),
+				PhysicalPath: childPath,
+				ConfigPaths:  configPaths,
+			})
+		}
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteRemovesGitSubcomponentDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeComponent(t, filepath.Join(root, "components", "widget"), `{"name":"widget"}`)
+
+	c := &Component{
+		PhysicalPath: root,
+		Subcomponents: []Component{
+			{Name: "widget", Source: "https://example.invalid/widget.git", Method: "git"},
+		},
+		Config: ComponentConfig{Subcomponents: map[string]ComponentConfig{"widget": {}}},
+	}
+
+	assert.NoError(t, c.Delete("widget"))
+	assert.NoDirExists(t, filepath.Join(root, "components", "widget"))
+	assert.Empty(t, c.Subcomponents)
+	assert.NotContains(t, c.Config.Subcomponents, "widget")
+}
+
+func TestDeleteRemovesSourceOnlySubcomponentDirectory(t *testing.T) {
+	root := t.TempDir()
+	// A plain Source-only subcomponent (no Method) installs directly under
+	// its own name, not under components/, per RelativePathTo's fallback.
+	writeComponent(t, filepath.Join(root, "widget"), `{"name":"widget"}`)
+
+	c := &Component{
+		PhysicalPath: root,
+		Subcomponents: []Component{
+			{Name: "widget", Source: "inline-widget"},
+		},
+	}
+
+	assert.NoError(t, c.Delete("widget"))
+	assert.NoDirExists(t, filepath.Join(root, "widget"))
+}
+
+func TestDeleteInlineSubcomponentDoesNotRemoveParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	// An inline subcomponent (no Source) has no installed directory of its
+	// own; RelativePathTo resolves to "./", i.e. the parent's own
+	// PhysicalPath. Deleting it must not touch the parent's files.
+	writeComponent(t, root, `{"name":"parent"}`)
+	siblingPath := filepath.Join(root, "sibling.txt")
+	assert.NoError(t, ioutil.WriteFile(siblingPath, []byte("unrelated"), 0644))
+
+	c := &Component{
+		PhysicalPath: root,
+		Subcomponents: []Component{
+			{Name: "inlinekid"},
+		},
+		Config: ComponentConfig{Subcomponents: map[string]ComponentConfig{"inlinekid": {}}},
+	}
+
+	assert.NoError(t, c.Delete("inlinekid"))
+	assert.FileExists(t, siblingPath)
+	assert.Empty(t, c.Subcomponents)
+	assert.NotContains(t, c.Config.Subcomponents, "inlinekid")
+}
+
+func TestDeleteErrorsWhenSubcomponentNotFound(t *testing.T) {
+	c := &Component{PhysicalPath: t.TempDir()}
+
+	err := c.Delete("missing")
+
+	assert.Error(t, err)
+}
+
+func TestStripSubcomponentKeyDoesNotCorruptHardLinkedCacheEntry(t *testing.T) {
+	// Simulate a subcomponent whose config/prod.json was hard-linked out of
+	// ComponentCache's shared cache entry (see copyTree), and confirm that
+	// stripping an orphan key rewrites the destination's own inode rather
+	// than mutating the cached original through the shared link.
+	cacheDir := t.TempDir()
+	cachedConfigPath := filepath.Join(cacheDir, "prod.json")
+	assert.NoError(t, ioutil.WriteFile(cachedConfigPath, []byte(`{"subcomponents":{"orphan":{},"kept":{}}}`), 0644))
+
+	destDir := t.TempDir()
+	destConfigPath := filepath.Join(destDir, "prod.json")
+	assert.NoError(t, os.Link(cachedConfigPath, destConfigPath))
+
+	assert.NoError(t, stripSubcomponentKey(destConfigPath, "orphan"))
+
+	destRaw, err := ioutil.ReadFile(destConfigPath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(destRaw), "orphan")
+	assert.Contains(t, string(destRaw), "kept")
+
+	cachedRaw, err := ioutil.ReadFile(cachedConfigPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(cachedRaw), "orphan", "the shared cache entry must survive the strip untouched")
+	assert.Contains(t, string(cachedRaw), "kept")
+}
+
+func TestPruneComponentTreeIsRaceFree(t *testing.T) {
+	// A wide fan-out so IterateComponentTree's worker pool genuinely runs
+	// several goroutines writing into PruneComponentTree's referenced-path
+	// tracking concurrently; run with `go test -race` to catch regressions.
+	root := buildFanOutTree(t, 32)
+
+	plan, err := PruneComponentTree(root, "common")
+
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Actions, "nothing is orphaned in a freshly built tree")
+}
+
+func TestPruneComponentTreeFindsOrphan(t *testing.T) {
+	root := buildFanOutTree(t, 4)
+	writeComponent(t, root+"/components/orphan", `{"name":"orphan"}`)
+
+	plan, err := PruneComponentTree(root, "common")
+
+	assert.NoError(t, err)
+	assert.Len(t, plan.Actions, 1)
+	assert.Equal(t, "orphan", plan.Actions[0].Name)
+}